@@ -0,0 +1,22 @@
+/* SPDX-License-Identifier: Apache-2.0 */
+/* Copyright(c) 2022 Wind River Systems, Inc. */
+
+package v1
+
+// DeletionPolicy controls what happens to a CR's system-side resource
+// when the CR itself is deleted.
+// +kubebuilder:validation:Enum=Orphan;Foreground;Background
+type DeletionPolicy string
+
+const (
+	// DeletionPolicyOrphan leaves the system-side resource in place.
+	DeletionPolicyOrphan DeletionPolicy = "Orphan"
+	// DeletionPolicyForeground blocks removal of the owning finalizer,
+	// and therefore of the CR itself, until the system-side resource has
+	// been deleted.
+	DeletionPolicyForeground DeletionPolicy = "Foreground"
+	// DeletionPolicyBackground removes the owning finalizer (and lets
+	// the CR be removed) immediately, while the system-side resource is
+	// cleaned up asynchronously.
+	DeletionPolicyBackground DeletionPolicy = "Background"
+)
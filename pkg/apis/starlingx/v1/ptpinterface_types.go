@@ -18,6 +18,13 @@ type PtpInterfaceSpec struct {
 	// InterfaceParameters contains a list of parameters assigned to the ptp interface
 	// +optional
 	InterfaceParameters []string `json:"parameters,omitempty"`
+
+	// DeletionPolicy defines what happens to the system resource backing
+	// this PtpInterface when this resource is deleted.  Defaults to
+	// Foreground, which blocks removal of this resource until the
+	// system-side resource has been deleted.
+	// +optional
+	DeletionPolicy DeletionPolicy `json:"deletionPolicy,omitempty"`
 }
 
 // PtpInterfaceStatus defines the observed state of a PtpInterface resource
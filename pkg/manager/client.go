@@ -0,0 +1,149 @@
+/* SPDX-License-Identifier: Apache-2.0 */
+/* Copyright(c) 2022 Wind River Systems, Inc. */
+
+package manager
+
+import (
+	"context"
+
+	"github.com/gophercloud/gophercloud"
+	"github.com/gophercloud/gophercloud/openstack"
+	perrors "github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/wind-river/cloud-platform-deployment-manager/pkg/apis/starlingx/v1"
+)
+
+// PlatformServiceType is the keystone catalog service type this operator
+// authenticates against once it has a gophercloud.ProviderClient for a
+// namespace.
+const PlatformServiceType = "platform"
+
+// BuildPlatformClient returns the cached *gophercloud.ServiceClient for
+// (namespace, DefaultSystemName), building and caching one first if none
+// exists yet.
+func (m *PlatformManager) BuildPlatformClient(namespace string) (*gophercloud.ServiceClient, error) {
+	return m.BuildPlatformClientFor(namespace, DefaultSystemName)
+}
+
+// BuildPlatformClientFor returns the cached *gophercloud.ServiceClient for
+// (namespace, systemName), authenticating and caching one if necessary.
+// The gophercloud.ProviderClient produced by authenticating against the
+// SystemEndpointSecretName secret is shared by every system subsequently
+// built for the same namespace (see ClientPool.Provider), so that a
+// second system in that namespace, e.g. a second region in a
+// distributed-cloud deployment, reuses the existing token instead of
+// re-authenticating. Transient failures while authenticating or looking
+// up the service endpoint go through WithRetry, so a caller running from
+// a reconciler should check the returned error with IsRequeueAfter and
+// return ctrl.Result{RequeueAfter: delay} rather than treating it as
+// fatal. Once built, the client's key is published via
+// publishActiveClientKey on the System CR in namespace whose name is
+// systemName, if there is one; for BuildPlatformClient that is only a
+// System CR literally named DefaultSystemName, and no other CR is updated.
+func (m *PlatformManager) BuildPlatformClientFor(namespace, systemName string) (*gophercloud.ServiceClient, error) {
+	key := SystemKey{Namespace: namespace, Name: systemName}
+
+	if c, ok := m.registry.pool.Get(key); ok {
+		return c, nil
+	}
+
+	var svcClient *gophercloud.ServiceClient
+	err := m.WithRetry(namespace, "client/"+key.String(), func() error {
+		provider, ok := m.registry.pool.Provider(namespace)
+		if !ok {
+			opts, authErr := authOptionsFor(m.GetKubernetesClient(), namespace)
+			if authErr != nil {
+				return NewFatalClientError(authErr.Error())
+			}
+
+			provider, authErr = openstack.AuthenticatedClient(opts)
+			if authErr != nil {
+				return perrors.Wrapf(authErr, "failed to authenticate system %q", key)
+			}
+
+			m.registry.pool.SetProvider(namespace, provider)
+		}
+
+		endpoint, endpointErr := provider.EndpointLocator(gophercloud.EndpointOpts{Type: PlatformServiceType})
+		if endpointErr != nil {
+			return perrors.Wrapf(endpointErr, "failed to locate %q endpoint for system %q", PlatformServiceType, key)
+		}
+
+		svcClient = &gophercloud.ServiceClient{ProviderClient: provider, Endpoint: endpoint}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	m.registry.pool.Put(key, svcClient)
+
+	ns := m.registry.getOrCreate(key)
+	m.lock.Lock()
+	ns.client = svcClient
+	m.lock.Unlock()
+
+	if err := m.publishActiveClientKey(namespace, key); err != nil {
+		log.Info("failed to publish active client key", "namespace", namespace, "system", key.Name, "error", err)
+	}
+
+	return svcClient, nil
+}
+
+// authOptionsFor reads the SystemEndpointSecretName secret from namespace
+// and translates its OS_* keys into a gophercloud.AuthOptions, the same
+// set of attributes an operator would otherwise export to authenticate
+// the "openstack" CLI against this system.
+func authOptionsFor(c client.Client, namespace string) (gophercloud.AuthOptions, error) {
+	secret := &corev1.Secret{}
+	name := client.ObjectKey{Namespace: namespace, Name: SystemEndpointSecretName}
+	if err := c.Get(context.TODO(), name, secret); err != nil {
+		return gophercloud.AuthOptions{}, perrors.Wrapf(err, "failed to get secret %q", name)
+	}
+
+	return gophercloud.AuthOptions{
+		IdentityEndpoint: string(secret.Data["OS_AUTH_URL"]),
+		Username:         string(secret.Data["OS_USERNAME"]),
+		Password:         string(secret.Data["OS_PASSWORD"]),
+		TenantName:       string(secret.Data["OS_PROJECT_NAME"]),
+		DomainName:       string(secret.Data["OS_USER_DOMAIN_NAME"]),
+	}, nil
+}
+
+// publishActiveClientKey records key on the ActiveClientKey status field
+// of the single System CR in namespace named key.Name, so that an
+// operator inspecting that CR can see which cached client is currently
+// authoritative without inspecting the manager's in-process registry.
+// Every other system in the namespace is left untouched, and the status
+// write itself is skipped once the field already holds key's value, so
+// that a client rebuilt for the same system (after a reset, or after
+// being aged out of the pool) does not churn the CR's resourceVersion.
+// A cache hit in BuildPlatformClientFor returns before reaching here.
+func (m *PlatformManager) publishActiveClientKey(namespace string, key SystemKey) error {
+	systems := &v1.SystemList{}
+	opts := client.ListOptions{}
+	opts.InNamespace(namespace)
+	if err := m.GetClient().List(context.TODO(), &opts, systems); err != nil {
+		return perrors.Wrap(err, "failed to query system list")
+	}
+
+	for i := range systems.Items {
+		system := &systems.Items[i]
+		if system.Name != key.Name {
+			continue
+		}
+
+		if system.Status.ActiveClientKey == key.String() {
+			return nil
+		}
+
+		system.Status.ActiveClientKey = key.String()
+
+		return perrors.Wrapf(m.GetClient().Status().Update(context.TODO(), system), "failed to update status of system %q", system.Name)
+	}
+
+	return nil
+}
@@ -0,0 +1,314 @@
+/* SPDX-License-Identifier: Apache-2.0 */
+/* Copyright(c) 2022 Wind River Systems, Inc. */
+
+// Package fake provides a deterministic, in-memory CloudManager
+// implementation for unit and envtest suites, so that reconciler tests
+// do not need a real StarlingX endpoint to talk to.
+package fake
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gophercloud/gophercloud"
+	perrors "github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/wind-river/cloud-platform-deployment-manager/pkg/manager"
+	"github.com/wind-river/cloud-platform-deployment-manager/pkg/manager/gc"
+)
+
+// NTPFixture is a single canned "intp" record served by the fake
+// platform client for list/get/update requests, keyed by ID.
+type NTPFixture struct {
+	ID         string `json:"uuid"`
+	Enabled    bool   `json:"enabled"`
+	NTPServers string `json:"ntpservers"`
+}
+
+// DefaultNTPFixtures is a small table-driven set of "intp" records
+// covering the list/get/update endpoints defined by
+// vendor/.../gophercloud/starlingx/inventory/v1/ntp, so that a
+// reconciler test can exercise a realistic response without a real
+// StarlingX endpoint.
+var DefaultNTPFixtures = []NTPFixture{
+	{ID: "intp-0", Enabled: true, NTPServers: "0.pool.ntp.org,1.pool.ntp.org"},
+}
+
+type systemState struct {
+	ready      bool
+	systemType manager.SystemType
+}
+
+// fakeMaxRetries bounds how many times WithRetry reports budget
+// remaining for a given key before simulating exhaustion, mirroring
+// manager.RetryManager at a size small enough for a test to reach
+// deterministically.
+const fakeMaxRetries = 3
+
+// fakeRetryDelay is the delay WithRetry reports via manager.IsRequeueAfter
+// while a key's simulated retry budget still has room.
+const fakeRetryDelay = time.Millisecond
+
+// FakePlatformManager is a deterministic, in-memory CloudManager backed
+// by an httptest.Server serving DefaultNTPFixtures instead of a real
+// StarlingX endpoint.  InjectError lets a test force BuildPlatformClient,
+// and WithRetry, to fail in order to exercise the retry and monitor paths
+// implemented by PlatformManager.
+type FakePlatformManager struct {
+	kubeClient client.Client
+	bus        *manager.NotifyBus
+	gc         *gc.GarbageCollector
+	server     *httptest.Server
+
+	lock     sync.Mutex
+	systems  map[string]*systemState
+	attempts map[string]int
+	monitors map[string]*manager.Monitor
+
+	InjectError error
+}
+
+var _ manager.CloudManager = (*FakePlatformManager)(nil)
+
+// NewFakePlatformManager returns a FakePlatformManager pre-populated
+// with objects and ready to serve DefaultNTPFixtures.  Call Close once
+// the test is done to stop the underlying httptest.Server.
+func NewFakePlatformManager(objects ...runtime.Object) *FakePlatformManager {
+	f := &FakePlatformManager{
+		kubeClient: fake.NewFakeClient(objects...),
+		bus:        manager.NewNotifyBus(),
+		gc:         gc.NewGarbageCollector(1),
+		systems:    make(map[string]*systemState),
+		attempts:   make(map[string]int),
+		monitors:   make(map[string]*manager.Monitor),
+	}
+	f.server = httptest.NewServer(newNTPHandler(DefaultNTPFixtures))
+
+	return f
+}
+
+// Close stops the underlying httptest.Server.
+func (f *FakePlatformManager) Close() {
+	f.server.Close()
+}
+
+func (f *FakePlatformManager) stateFor(namespace string) *systemState {
+	s, ok := f.systems[namespace]
+	if !ok {
+		s = &systemState{}
+		f.systems[namespace] = s
+	}
+	return s
+}
+
+func (f *FakePlatformManager) ResetPlatformClient(namespace string) error {
+	return nil
+}
+
+func (f *FakePlatformManager) ResetPlatformClientFor(namespace, systemName string) error {
+	return nil
+}
+
+func (f *FakePlatformManager) GetPlatformClient(namespace string) *gophercloud.ServiceClient {
+	return f.GetPlatformClientFor(namespace, manager.DefaultSystemName)
+}
+
+func (f *FakePlatformManager) GetPlatformClientFor(namespace, systemName string) *gophercloud.ServiceClient {
+	c, err := f.BuildPlatformClientFor(namespace, systemName)
+	if err != nil {
+		return nil
+	}
+	return c
+}
+
+func (f *FakePlatformManager) GetKubernetesClient() client.Client {
+	return f.kubeClient
+}
+
+// BuildPlatformClient returns a *gophercloud.ServiceClient pointed at
+// the in-process httptest.Server, or InjectError if the test has set
+// one.
+func (f *FakePlatformManager) BuildPlatformClient(namespace string) (*gophercloud.ServiceClient, error) {
+	return f.BuildPlatformClientFor(namespace, manager.DefaultSystemName)
+}
+
+// BuildPlatformClientFor ignores systemName, since every system in every
+// namespace shares the same in-process httptest.Server, and returns a
+// *gophercloud.ServiceClient pointed at it, or InjectError if the test
+// has set one.
+func (f *FakePlatformManager) BuildPlatformClientFor(namespace, systemName string) (*gophercloud.ServiceClient, error) {
+	if f.InjectError != nil {
+		return nil, f.InjectError
+	}
+
+	return &gophercloud.ServiceClient{
+		ProviderClient: &gophercloud.ProviderClient{},
+		Endpoint:       f.server.URL + "/",
+	}, nil
+}
+
+func (f *FakePlatformManager) NotifySystemDependencies(namespace string) error {
+	return nil
+}
+
+func (f *FakePlatformManager) NotifyResource(object runtime.Object) error {
+	return nil
+}
+
+func (f *FakePlatformManager) Bus() *manager.NotifyBus {
+	return f.bus
+}
+
+// WithRetry runs fn and classifies the result the same way
+// PlatformManager.WithRetry does, using a per-key attempt counter instead
+// of a real token-bucket RetryManager, so a test can deterministically
+// drive a caller through the backoff/max-retries/fatal-classification
+// paths without waiting on real time.  If InjectError is set and fn
+// succeeds, InjectError is classified in place of fn's nil result (an
+// error returned by fn takes precedence), so a test can also exercise a
+// caller's handling of a failure originating from BuildPlatformClient.
+func (f *FakePlatformManager) WithRetry(namespace string, key string, fn manager.RetryableFunc) error {
+	err := fn()
+	if err == nil && f.InjectError != nil {
+		err = f.InjectError
+	}
+
+	if err == nil {
+		f.lock.Lock()
+		delete(f.attempts, key)
+		f.lock.Unlock()
+		return nil
+	}
+
+	if _, ok := perrors.Cause(err).(manager.FatalClientError); ok {
+		f.lock.Lock()
+		delete(f.attempts, key)
+		f.lock.Unlock()
+		return err
+	}
+
+	f.lock.Lock()
+	f.attempts[key]++
+	attempts := f.attempts[key]
+	f.lock.Unlock()
+
+	if attempts > fakeMaxRetries {
+		f.lock.Lock()
+		delete(f.attempts, key)
+		f.lock.Unlock()
+		return perrors.Wrapf(err, "exceeded maximum retries for %q", key)
+	}
+
+	return manager.NewRequeueAfter(fakeRetryDelay, err)
+}
+
+func (f *FakePlatformManager) SetSystemReady(namespace string, value bool) {
+	f.lock.Lock()
+	defer func() { f.lock.Unlock() }()
+
+	f.stateFor(namespace).ready = value
+}
+
+func (f *FakePlatformManager) GetSystemReady(namespace string) bool {
+	f.lock.Lock()
+	defer func() { f.lock.Unlock() }()
+
+	return f.stateFor(namespace).ready
+}
+
+func (f *FakePlatformManager) SetSystemType(namespace string, value manager.SystemType) {
+	f.lock.Lock()
+	defer func() { f.lock.Unlock() }()
+
+	f.stateFor(namespace).systemType = value
+}
+
+func (f *FakePlatformManager) GetSystemType(namespace string) manager.SystemType {
+	f.lock.Lock()
+	defer func() { f.lock.Unlock() }()
+
+	return f.stateFor(namespace).systemType
+}
+
+// StartMonitor records monitor under its key and starts it against this
+// FakePlatformManager, mirroring PlatformManager.StartMonitor, so that a
+// test can trigger the monitor's own completion logic (e.g. a timer or a
+// watch) and observe the reconcile it causes, then returns WaitForMonitor
+// the same way the real implementation does.
+func (f *FakePlatformManager) StartMonitor(monitor *manager.Monitor, message string) error {
+	f.lock.Lock()
+	key := monitor.GetKey()
+	f.monitors[key] = monitor
+	f.lock.Unlock()
+
+	monitor.Start(f)
+
+	return manager.NewWaitForMonitor(message)
+}
+
+// CancelMonitor stops and forgets the monitor previously started for
+// object's key, mirroring PlatformManager.CancelMonitor.
+func (f *FakePlatformManager) CancelMonitor(object runtime.Object) {
+	key := manager.BuildMonitorKey(object)
+
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if monitor, ok := f.monitors[key]; ok {
+		monitor.Stop()
+		delete(f.monitors, key)
+	}
+}
+
+func (f *FakePlatformManager) GarbageCollector() *gc.GarbageCollector {
+	return f.gc
+}
+
+// newNTPHandler serves fixtures for the list ("/intp"), get and update
+// ("/intp/{id}") endpoints used by the ntp gophercloud client.
+func newNTPHandler(fixtures []NTPFixture) http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/intp", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "unsupported method", http.StatusMethodNotAllowed)
+			return
+		}
+		writeJSON(w, map[string]interface{}{"intp": fixtures})
+	})
+
+	mux.HandleFunc("/intp/", func(w http.ResponseWriter, r *http.Request) {
+		id := strings.TrimPrefix(r.URL.Path, "/intp/")
+
+		for i := range fixtures {
+			if fixtures[i].ID != id {
+				continue
+			}
+
+			switch r.Method {
+			case http.MethodGet, http.MethodPatch:
+				writeJSON(w, fixtures[i])
+			default:
+				http.Error(w, "unsupported method", http.StatusMethodNotAllowed)
+			}
+
+			return
+		}
+
+		http.NotFound(w, r)
+	})
+
+	return mux
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,120 @@
+/* SPDX-License-Identifier: Apache-2.0 */
+/* Copyright(c) 2022 Wind River Systems, Inc. */
+
+package fake_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	perrors "github.com/pkg/errors"
+
+	"github.com/wind-river/cloud-platform-deployment-manager/pkg/manager"
+	"github.com/wind-river/cloud-platform-deployment-manager/pkg/manager/fake"
+)
+
+// TestBuildPlatformClientServesNTPFixtures confirms that the
+// httptest.Server backing a FakePlatformManager actually serves
+// DefaultNTPFixtures at the endpoint BuildPlatformClient returns, so a
+// reconciler test can hit it exactly the way it would hit a real
+// StarlingX endpoint.
+func TestBuildPlatformClientServesNTPFixtures(t *testing.T) {
+	f := fake.NewFakePlatformManager()
+	defer f.Close()
+
+	c, err := f.BuildPlatformClient("test-namespace")
+	if err != nil {
+		t.Fatalf("BuildPlatformClient returned an error: %v", err)
+	}
+
+	resp, err := http.Get(c.Endpoint + "intp")
+	if err != nil {
+		t.Fatalf("failed to GET %s: %v", c.Endpoint+"intp", err)
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		Intp []fake.NTPFixture `json:"intp"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	if len(body.Intp) != len(fake.DefaultNTPFixtures) {
+		t.Fatalf("expected %d ntp fixtures, got %d", len(fake.DefaultNTPFixtures), len(body.Intp))
+	}
+
+	if body.Intp[0].ID != fake.DefaultNTPFixtures[0].ID {
+		t.Errorf("expected fixture id %q, got %q", fake.DefaultNTPFixtures[0].ID, body.Intp[0].ID)
+	}
+}
+
+// TestBuildPlatformClientInjectError confirms that InjectError is
+// returned from BuildPlatformClient instead of a real client once set.
+func TestBuildPlatformClientInjectError(t *testing.T) {
+	f := fake.NewFakePlatformManager()
+	defer f.Close()
+
+	f.InjectError = manager.NewClientError("boom")
+
+	if _, err := f.BuildPlatformClient("test-namespace"); err != f.InjectError {
+		t.Fatalf("expected InjectError to be returned, got %v", err)
+	}
+}
+
+// TestWithRetryRequeuesTransientErrors confirms that WithRetry reports a
+// RequeueAfter error while the simulated retry budget has room, and
+// surfaces the "exceeded maximum retries" error once it is exhausted.
+func TestWithRetryRequeuesTransientErrors(t *testing.T) {
+	f := fake.NewFakePlatformManager()
+	defer f.Close()
+
+	attempts := 0
+	fn := func() error {
+		attempts++
+		return manager.NewClientError("transient")
+	}
+
+	var lastErr error
+	for i := 0; i < 10; i++ {
+		lastErr = f.WithRetry("test-namespace", "some-key", fn)
+		if _, ok := manager.IsRequeueAfter(lastErr); !ok {
+			break
+		}
+	}
+
+	if attempts == 0 {
+		t.Fatal("expected fn to have been invoked at least once")
+	}
+
+	if _, ok := manager.IsRequeueAfter(lastErr); ok {
+		t.Fatalf("expected retries to eventually be exhausted, got RequeueAfter: %v", lastErr)
+	}
+}
+
+// TestWithRetryStopsOnFatalError confirms that WithRetry returns a
+// FatalClientError immediately, without ever reporting a RequeueAfter,
+// and that it does not retry fn.
+func TestWithRetryStopsOnFatalError(t *testing.T) {
+	f := fake.NewFakePlatformManager()
+	defer f.Close()
+
+	attempts := 0
+	err := f.WithRetry("test-namespace", "fatal-key", func() error {
+		attempts++
+		return manager.NewFatalClientError("unrecoverable")
+	})
+
+	if attempts != 1 {
+		t.Fatalf("expected fn to run exactly once, ran %d times", attempts)
+	}
+
+	if _, ok := manager.IsRequeueAfter(err); ok {
+		t.Fatalf("expected a fatal error, got RequeueAfter: %v", err)
+	}
+
+	if _, ok := perrors.Cause(err).(manager.FatalClientError); !ok {
+		t.Fatalf("expected a FatalClientError, got %v", err)
+	}
+}
@@ -0,0 +1,311 @@
+/* SPDX-License-Identifier: Apache-2.0 */
+/* Copyright(c) 2022 Wind River Systems, Inc. */
+
+// Package gc implements a generic garbage collector for the StarlingX-side
+// resources created by this operator, inspired by the Kubernetes generic
+// garbage collector.  A reconciler registers a CleanupFunc for the
+// GroupVersionKind it owns; Watch discovers every StarlingX GVK through an
+// unstructured informer from the manager's cache (it caches the full
+// object, not only its metadata) and enqueues any object that carries a deletion
+// timestamp and OwnerUIDFinalizer, so that transient platform errors are
+// retried instead of leaking the resource.
+package gc
+
+import (
+	"context"
+	"sync"
+
+	perrors "github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	toolscache "k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+	ctrlmanager "sigs.k8s.io/controller-runtime/pkg/manager"
+	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
+
+	"github.com/wind-river/cloud-platform-deployment-manager/pkg/apis/starlingx/v1"
+)
+
+var log = logf.Log.WithName("gc")
+
+// OwnerUIDFinalizer is added by each reconciler to the StarlingX-owning
+// CR when it creates the corresponding platform-side resource.  Its
+// continued presence after the CR's deletion timestamp is set tells the
+// GarbageCollector that platform-side cleanup is still outstanding.
+const OwnerUIDFinalizer = "starlingx.windriver.com/owner-uid"
+
+// DefaultConcurrentWorkers is the default number of workers run per
+// registered kind.  No command line flag overrides it.
+const DefaultConcurrentWorkers = 2
+
+// DefaultMaxRetries bounds how many times cleanup of a single item is
+// retried before the GarbageCollector gives up on it and reports the
+// last error to whoever is waiting on its completion channel.
+const DefaultMaxRetries = 15
+
+// DeletionPolicy controls what happens to a CR's platform-side resource
+// when the CR itself is deleted.  It is defined in the starlingx v1 API
+// package, since it is part of every affected CR's spec; this is an
+// alias so existing callers of gc.DeletionPolicy keep working.
+type DeletionPolicy = v1.DeletionPolicy
+
+const (
+	DeletionPolicyOrphan     = v1.DeletionPolicyOrphan
+	DeletionPolicyForeground = v1.DeletionPolicyForeground
+	DeletionPolicyBackground = v1.DeletionPolicyBackground
+)
+
+// CleanupFunc deletes the platform-side resource that key refers to. It
+// is registered per GroupVersionKind by the reconciler that owns that
+// kind, since only that reconciler knows how to translate a
+// NamespacedName into a gophercloud delete call.
+type CleanupFunc func(ctx context.Context, key types.NamespacedName) error
+
+// PolicyFunc returns the DeletionPolicy recorded on obj's spec.  It is
+// supplied to Watch so that the GarbageCollector does not need to know
+// how to decode every CR kind's spec itself.
+type PolicyFunc func(obj *unstructured.Unstructured) DeletionPolicy
+
+// DefaultPolicyFunc reads the "deletionPolicy" field out of obj's spec
+// directly, via unstructured.NestedString, instead of type-asserting obj
+// to one of the typed CR specs, so the GarbageCollector does not need to
+// import, or type-switch on, each kind's Go type. Of the kinds subject to
+// garbage collection only PtpInterfaceSpec declares this field; System,
+// Host, PlatformNetwork, DataNetwork and PTPInstance specs have no
+// "deletionPolicy" field and so always get the default. A missing or unrecognized
+// value defaults to Foreground, the safer of the two active policies,
+// so that a CR predating this field is not silently orphaned.
+func DefaultPolicyFunc(obj *unstructured.Unstructured) DeletionPolicy {
+	value, found, err := unstructured.NestedString(obj.Object, "spec", "deletionPolicy")
+	if err != nil || !found {
+		return DeletionPolicyForeground
+	}
+
+	switch DeletionPolicy(value) {
+	case DeletionPolicyOrphan, DeletionPolicyBackground:
+		return DeletionPolicy(value)
+	default:
+		return DeletionPolicyForeground
+	}
+}
+
+type item struct {
+	key    types.NamespacedName
+	policy DeletionPolicy
+	// done receives the terminal error (nil on success) once cleanup of
+	// key finishes.  Only Foreground deletions populate this: the
+	// reconciler blocks on it, with a timeout, before removing
+	// OwnerUIDFinalizer.  Background deletions leave it nil and drop the
+	// finalizer immediately, so nobody is listening for completion.
+	done chan error
+}
+
+// GarbageCollector drives deletion of the platform-side resource
+// belonging to a System, Host, PlatformNetwork, DataNetwork, PTPInstance
+// or PtpInterface CR once that CR has been deleted.  Each registered
+// GroupVersionKind gets its own rate limited workqueue and worker pool so
+// that a backlog of deletions for one kind can never starve another.
+type GarbageCollector struct {
+	workers int
+
+	lock     sync.Mutex
+	cleanups map[schema.GroupVersionKind]CleanupFunc
+	queues   map[schema.GroupVersionKind]workqueue.RateLimitingInterface
+}
+
+// NewGarbageCollector returns a GarbageCollector that runs workers
+// concurrent workers per registered kind.  A workers value of 0 or less
+// falls back to DefaultConcurrentWorkers.
+func NewGarbageCollector(workers int) *GarbageCollector {
+	if workers <= 0 {
+		workers = DefaultConcurrentWorkers
+	}
+
+	return &GarbageCollector{
+		workers:  workers,
+		cleanups: make(map[schema.GroupVersionKind]CleanupFunc),
+		queues:   make(map[schema.GroupVersionKind]workqueue.RateLimitingInterface),
+	}
+}
+
+// Register associates fn with gvk and starts the worker pool that will
+// drain its deletion queue.  Reconcilers call this once from
+// SetupWithManager.
+func (gc *GarbageCollector) Register(gvk schema.GroupVersionKind, fn CleanupFunc) {
+	gc.lock.Lock()
+	defer gc.lock.Unlock()
+
+	gc.cleanups[gvk] = fn
+
+	if _, ok := gc.queues[gvk]; ok {
+		return
+	}
+
+	queue := workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), gvk.Kind+"-gc")
+	gc.queues[gvk] = queue
+
+	for i := 0; i < gc.workers; i++ {
+		go gc.runWorker(gvk, queue)
+	}
+}
+
+// Watch discovers every gvk in gvks through mgr's cache, using an
+// unstructured informer (full objects, not metadata only), and enqueues
+// any object it observes with a deletion timestamp and OwnerUIDFinalizer
+// still set.  policyOf is consulted to classify each object's
+// DeletionPolicy.  Watch sets up an informer for every gvk whether or not
+// a CleanupFunc has been Register-ed for it; events for a gvk with none
+// are logged and dropped by Enqueue.
+func (gc *GarbageCollector) Watch(mgr ctrlmanager.Manager, gvks []schema.GroupVersionKind, policyOf PolicyFunc) error {
+	for _, gvk := range gvks {
+		gvk := gvk // capture for the closures below
+
+		object := &unstructured.Unstructured{}
+		object.SetGroupVersionKind(gvk)
+
+		informer, err := mgr.GetCache().GetInformer(object)
+		if err != nil {
+			return perrors.Wrapf(err, "failed to get informer for %s", gvk.Kind)
+		}
+
+		informer.AddEventHandler(toolscache.ResourceEventHandlerFuncs{
+			UpdateFunc: func(_, newObj interface{}) {
+				gc.handleEvent(gvk, newObj, policyOf)
+			},
+			DeleteFunc: func(obj interface{}) {
+				gc.handleEvent(gvk, obj, policyOf)
+			},
+		})
+	}
+
+	return nil
+}
+
+// handleEvent enqueues obj for cleanup if it is marked for deletion and
+// still carries OwnerUIDFinalizer.
+func (gc *GarbageCollector) handleEvent(gvk schema.GroupVersionKind, obj interface{}, policyOf PolicyFunc) {
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return
+	}
+
+	if accessor.GetDeletionTimestamp() == nil {
+		return
+	}
+
+	if !containsString(accessor.GetFinalizers(), OwnerUIDFinalizer) {
+		return
+	}
+
+	unstructuredObj, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return
+	}
+
+	key := types.NamespacedName{Namespace: accessor.GetNamespace(), Name: accessor.GetName()}
+	gc.Enqueue(gvk, key, policyOf(unstructuredObj))
+}
+
+// Enqueue schedules the platform-side resource for key to be deleted
+// according to policy.  A reconciler calls this once it observes that
+// its CR carries a deletion timestamp and OwnerUIDFinalizer, rather than
+// deleting the platform-side resource inline.
+//
+// For DeletionPolicyForeground the returned channel receives the
+// terminal error (nil on success) once cleanup completes; the caller
+// should block on it (with its own timeout) before removing
+// OwnerUIDFinalizer. DeletionPolicyBackground returns a nil channel: the
+// finalizer can be dropped immediately and cleanup proceeds
+// asynchronously. DeletionPolicyOrphan enqueues nothing and also returns
+// nil.
+func (gc *GarbageCollector) Enqueue(gvk schema.GroupVersionKind, key types.NamespacedName, policy DeletionPolicy) <-chan error {
+	if policy == DeletionPolicyOrphan {
+		log.Info("deletion policy is Orphan, leaving platform resource in place", "kind", gvk.Kind, "key", key)
+		return nil
+	}
+
+	gc.lock.Lock()
+	queue, ok := gc.queues[gvk]
+	gc.lock.Unlock()
+
+	if !ok {
+		log.Info("no cleanup function registered for kind, dropping", "kind", gvk.Kind, "key", key)
+		return nil
+	}
+
+	var done chan error
+	if policy == DeletionPolicyForeground {
+		done = make(chan error, 1)
+	}
+
+	queue.Add(item{key: key, policy: policy, done: done})
+
+	return done
+}
+
+func (gc *GarbageCollector) runWorker(gvk schema.GroupVersionKind, queue workqueue.RateLimitingInterface) {
+	for gc.processNextItem(gvk, queue) {
+	}
+}
+
+func (gc *GarbageCollector) processNextItem(gvk schema.GroupVersionKind, queue workqueue.RateLimitingInterface) bool {
+	obj, shutdown := queue.Get()
+	if shutdown {
+		return false
+	}
+	defer queue.Done(obj)
+
+	it := obj.(item)
+
+	gc.lock.Lock()
+	fn := gc.cleanups[gvk]
+	gc.lock.Unlock()
+
+	if fn == nil {
+		queue.Forget(obj)
+		gc.complete(it, perrors.Errorf("no cleanup function registered for %s", gvk.Kind))
+		return true
+	}
+
+	err := fn(context.Background(), it.key)
+	if err == nil {
+		queue.Forget(obj)
+		log.Info("platform resource cleaned up", "kind", gvk.Kind, "key", it.key, "policy", it.policy)
+		gc.complete(it, nil)
+		return true
+	}
+
+	if queue.NumRequeues(obj) >= DefaultMaxRetries {
+		queue.Forget(obj)
+		log.Info("giving up on platform resource cleanup", "kind", gvk.Kind, "key", it.key, "error", perrors.Cause(err))
+		gc.complete(it, err)
+		return true
+	}
+
+	log.Info("retrying platform resource cleanup", "kind", gvk.Kind, "key", it.key, "error", perrors.Cause(err))
+	queue.AddRateLimited(obj)
+
+	return true
+}
+
+// complete signals it.done, if a caller is waiting on it.
+func (gc *GarbageCollector) complete(it item, err error) {
+	if it.done == nil {
+		return
+	}
+
+	it.done <- err
+	close(it.done)
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+
+	return false
+}
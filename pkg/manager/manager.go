@@ -8,10 +8,12 @@ import (
 	"github.com/gophercloud/gophercloud"
 	perrors "github.com/pkg/errors"
 	"github.com/wind-river/cloud-platform-deployment-manager/pkg/apis/starlingx/v1"
+	"github.com/wind-river/cloud-platform-deployment-manager/pkg/manager/gc"
 	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
@@ -42,17 +44,23 @@ const (
 // coordinate certain function across different controllers.
 type CloudManager interface {
 	ResetPlatformClient(namespace string) error
+	ResetPlatformClientFor(namespace, systemName string) error
 	GetPlatformClient(namespace string) *gophercloud.ServiceClient
+	GetPlatformClientFor(namespace, systemName string) *gophercloud.ServiceClient
 	GetKubernetesClient() client.Client
 	BuildPlatformClient(namespace string) (*gophercloud.ServiceClient, error)
+	BuildPlatformClientFor(namespace, systemName string) (*gophercloud.ServiceClient, error)
 	NotifySystemDependencies(namespace string) error
 	NotifyResource(object runtime.Object) error
+	Bus() *NotifyBus
+	WithRetry(namespace string, key string, fn RetryableFunc) error
 	SetSystemReady(namespace string, value bool)
 	GetSystemReady(namespace string) bool
 	SetSystemType(namespace string, value SystemType)
 	GetSystemType(namespace string) SystemType
 	StartMonitor(monitor *Monitor, message string) error
 	CancelMonitor(object runtime.Object)
+	GarbageCollector() *gc.GarbageCollector
 }
 
 type SystemType string
@@ -75,21 +83,75 @@ type SystemNamespace struct {
 	client     *gophercloud.ServiceClient
 	ready      bool
 	systemType SystemType
+	paused     bool
 }
 
 type PlatformManager struct {
 	manager.Manager
 	lock     sync.Mutex
-	systems  map[string]*SystemNamespace
+	registry *SystemRegistry
 	monitors map[string]*Monitor
+	bus      *NotifyBus
+	retries  map[string]*RetryManager
+	gc       *gc.GarbageCollector
 }
 
+// ConcurrentGCWorkers is the worker pool size handed to the
+// GarbageCollector started by GetInstance.  It is a package variable,
+// rather than a constant, so that it can be wired to a
+// "--concurrent-gc-workers" command line flag.
+var ConcurrentGCWorkers = gc.DefaultConcurrentWorkers
+
+// platformGVKs lists every StarlingX CR kind whose system-side resource
+// is subject to garbage collection once the CR itself is deleted; this
+// is the same set of kinds named in the GarbageCollector type's doc
+// comment.
+var platformGVKs = []schema.GroupVersionKind{
+	{Group: v1.Group, Version: v1.Version, Kind: v1.KindSystem},
+	{Group: v1.Group, Version: v1.Version, Kind: v1.KindHost},
+	{Group: v1.Group, Version: v1.Version, Kind: v1.KindPlatformNetwork},
+	{Group: v1.Group, Version: v1.Version, Kind: v1.KindDataNetwork},
+	{Group: v1.Group, Version: v1.Version, Kind: v1.KindPTPInstance},
+	{Group: v1.Group, Version: v1.Version, Kind: v1.KindPTPInterface},
+}
+
+// NewPlatformManager returns a PlatformManager wrapping manager and
+// immediately starts the unstructured informer watches that drive its
+// GarbageCollector for every kind in platformGVKs.  Reconcilers still
+// need to call GarbageCollector().Register for each kind they own, from
+// their own SetupWithManager, before any watched deletion actually gets
+// cleaned up; until a kind is registered, Watch's discovery of its
+// deletions is logged and dropped rather than lost silently (see
+// GarbageCollector.Enqueue).
 func NewPlatformManager(manager manager.Manager) CloudManager {
-	return &PlatformManager{
+	m := &PlatformManager{
 		Manager:  manager,
-		systems:  make(map[string]*SystemNamespace),
+		registry: NewSystemRegistry(),
 		monitors: make(map[string]*Monitor),
+		bus:      NewNotifyBus(),
+		retries:  make(map[string]*RetryManager),
+		gc:       gc.NewGarbageCollector(ConcurrentGCWorkers),
+	}
+
+	if err := m.gc.Watch(manager, platformGVKs, gc.DefaultPolicyFunc); err != nil {
+		log.Info("failed to start garbage collector watches", "error", err)
 	}
+
+	return m
+}
+
+// GarbageCollector returns the GarbageCollector shared by every
+// reconciler, used to drive deletion of the platform-side resource once
+// its owning CR has been removed.
+func (m *PlatformManager) GarbageCollector() *gc.GarbageCollector {
+	return m.gc
+}
+
+// Bus returns the NotifyBus used to deliver resource notifications
+// without mutating the resources being watched.  Controllers subscribe
+// to it from SetupWithManager for each GroupVersionKind they care about.
+func (m *PlatformManager) Bus() *NotifyBus {
+	return m.bus
 }
 
 // BaseError defines a common Error implementation for all manager errors
@@ -146,32 +208,80 @@ func getNextCount(value string) string {
 	return strconv.Itoa(count + 1)
 }
 
-func (m *PlatformManager) NotifySystemController(namespace string) error {
+// isNamespacePaused returns whether the System CR for namespace carries
+// the PausedAnnotation.  It also detects transitions and emits a
+// Paused/Unpaused Event on the System CR so that operators can see the
+// state change without having to poll the annotation.
+func (m *PlatformManager) isNamespacePaused(namespace string) bool {
 	systems := &v1.SystemList{}
 	opts := client.ListOptions{}
 	opts.InNamespace(namespace)
-	err := m.GetClient().List(context.TODO(), &opts, systems)
-	if err != nil {
-		err = perrors.Wrap(err, "failed to query system list")
-		return err
+	if err := m.GetClient().List(context.TODO(), &opts, systems); err != nil {
+		log.Info("failed to query system list for pause check", "namespace", namespace, "error", err)
+		return false
 	}
 
-	// There should only be a single system, but for the sake of completeness
-	// update any instance returned by the API.
-	for _, obj := range systems.Items {
-		count := getNextCount(obj.Annotations[NotificationCountKey])
-		obj.Annotations[NotificationCountKey] = count
+	paused := false
+	for i := range systems.Items {
+		system := &systems.Items[i]
+		if IsPaused(system) {
+			paused = true
+		}
+
+		m.recordPauseTransition(namespace, system, IsPaused(system))
+	}
 
-		err := m.GetClient().Update(context.TODO(), &obj)
+	return paused
+}
+
+// recordPauseTransition emits a "Paused" or "Unpaused" Event on obj the
+// first time its pause state is observed to differ from the cached
+// state for namespace.
+func (m *PlatformManager) recordPauseTransition(namespace string, obj runtime.Object, paused bool) {
+	ns := m.registry.getOrCreate(defaultKey(namespace))
+
+	m.lock.Lock()
+	changed := ns.paused != paused
+	ns.paused = paused
+	m.lock.Unlock()
+
+	if !changed {
+		return
+	}
+
+	if paused {
+		m.GetRecorder("platform-manager").Event(obj, "Normal", "Paused", "reconciliation has been paused")
+	} else {
+		m.GetRecorder("platform-manager").Event(obj, "Normal", "Unpaused", "reconciliation has been resumed")
+	}
+}
+
+func (m *PlatformManager) NotifySystemController(namespace string) error {
+	return m.WithRetry(namespace, "system-controller", func() error {
+		systems := &v1.SystemList{}
+		opts := client.ListOptions{}
+		opts.InNamespace(namespace)
+		err := m.GetClient().List(context.TODO(), &opts, systems)
 		if err != nil {
-			err = perrors.Wrap(err, "failed to notify system controller")
-			return err
+			return perrors.Wrap(err, "failed to query system list")
 		}
 
-		log.Info("system controller has been notified", "name", obj.Name)
-	}
+		// There should only be a single system, but for the sake of completeness
+		// update any instance returned by the API.
+		for _, obj := range systems.Items {
+			count := getNextCount(obj.Annotations[NotificationCountKey])
+			obj.Annotations[NotificationCountKey] = count
 
-	return nil
+			err := m.GetClient().Update(context.TODO(), &obj)
+			if err != nil {
+				return perrors.Wrap(err, "failed to notify system controller")
+			}
+
+			log.Info("system controller has been notified", "name", obj.Name)
+		}
+
+		return nil
+	})
 }
 
 // systemDependencies defines the list of controllers to be notified on a
@@ -196,10 +306,17 @@ var systemDependencies = []schema.GroupVersionKind{
 		Kind:    v1.KindPTPInterface},
 }
 
-// notifyControllers updates an annotation on each of the listed controller
-// kinds to force each to re-run its reconcile loop.  This should only be
-// executed by the system controller.
+// notifyControllers forces each of the listed controller kinds to re-run
+// its reconcile loop.  When the NotifyBus is enabled this simply
+// publishes the affected objects on the bus so that no CR mutation
+// occurs.  Otherwise it falls back to the legacy behaviour of bumping an
+// annotation on each of the listed controller kinds.  This should only
+// be executed by the system controller.
 func (m *PlatformManager) notifyControllers(namespace string, gvkList []schema.GroupVersionKind) error {
+	if EnableNotifyBus {
+		return m.publishControllers(namespace, gvkList)
+	}
+
 	for _, gvk := range gvkList {
 		objects := &unstructured.UnstructuredList{}
 		objects.SetGroupVersionKind(gvk)
@@ -224,7 +341,10 @@ func (m *PlatformManager) notifyControllers(namespace string, gvkList []schema.G
 
 				obj.SetAnnotations(annotations)
 
-				err := m.GetClient().Update(context.TODO(), &obj)
+				retryKey := obj.GetKind() + "/" + obj.GetNamespace() + "/" + obj.GetName()
+				err := m.WithRetry(namespace, retryKey, func() error {
+					return m.GetClient().Update(context.TODO(), &obj)
+				})
 				if err != nil {
 					err = perrors.Wrapf(err, "failed to notify %s controller", obj.GetKind())
 					return err
@@ -238,9 +358,44 @@ func (m *PlatformManager) notifyControllers(namespace string, gvkList []schema.G
 	return nil
 }
 
+// publishControllers lists each of the listed controller kinds and
+// publishes their keys on the NotifyBus.  Unlike notifyControllers it
+// performs no Update call against the resources found, so it never bumps
+// their resourceVersion.
+func (m *PlatformManager) publishControllers(namespace string, gvkList []schema.GroupVersionKind) error {
+	for _, gvk := range gvkList {
+		objects := &unstructured.UnstructuredList{}
+		objects.SetGroupVersionKind(gvk)
+		opts := client.ListOptions{}
+		opts.InNamespace(namespace)
+		err := m.GetClient().List(context.TODO(), &opts, objects)
+		if err != nil {
+			err = perrors.Wrapf(err, "failed to query %s list", gvk.Kind)
+			return err
+		}
+
+		for _, obj := range objects.Items {
+			switch obj.GetKind() {
+			case v1.KindHost, v1.KindHostProfile, v1.KindPlatformNetwork, v1.KindDataNetwork, v1.KindPTPInstance, v1.KindPTPInterface:
+				key := types.NamespacedName{Namespace: obj.GetNamespace(), Name: obj.GetName()}
+				m.bus.Publish(gvk, key)
+
+				log.Info("controller has been notified", "name", obj.GetName(), "kind", obj.GetKind())
+			}
+		}
+	}
+
+	return nil
+}
+
 // notifyController updates an annotation on a single controller to force it
-// to re-run its reconcile loop.
+// to re-run its reconcile loop.  This is only used as a fallback when the
+// NotifyBus is disabled; see publishController for the default path.
 func (m *PlatformManager) notifyController(object runtime.Object) error {
+	if EnableNotifyBus {
+		return m.publishController(object)
+	}
+
 	key, err := client.ObjectKeyFromObject(object)
 	if err != nil {
 		return err
@@ -274,7 +429,9 @@ func (m *PlatformManager) notifyController(object runtime.Object) error {
 		return err
 	}
 
-	err = m.GetClient().Update(context.TODO(), result)
+	err = m.WithRetry(key.Namespace, "resource/"+key.String(), func() error {
+		return m.GetClient().Update(context.TODO(), result)
+	})
 	if err != nil {
 		err = perrors.Wrapf(err, "failed to notify host controller")
 		return err
@@ -285,11 +442,52 @@ func (m *PlatformManager) notifyController(object runtime.Object) error {
 	return nil
 }
 
+// publishController publishes a single object on the NotifyBus.  Unlike
+// notifyController it performs no Get/Update round-trip against the
+// resource, so it never bumps its resourceVersion.
+func (m *PlatformManager) publishController(object runtime.Object) error {
+	key, err := client.ObjectKeyFromObject(object)
+	if err != nil {
+		return err
+	}
+
+	gvks, _, err := m.GetScheme().ObjectKinds(object)
+	if err != nil {
+		return perrors.Wrapf(err, "failed to determine kind of resource %+v", key)
+	}
+
+	for _, gvk := range gvks {
+		m.bus.Publish(gvk, key)
+	}
+
+	log.V(2).Info("controller has been notified", "key", key)
+
+	return nil
+}
+
 func (m *PlatformManager) NotifySystemDependencies(namespace string) error {
+	if m.isNamespacePaused(namespace) {
+		log.V(2).Info("skipping dependency notification for paused namespace", "namespace", namespace)
+		return nil
+	}
+
+	// notifyControllers/publishControllers list and notify every
+	// dependent object in namespace; they take no system key and so
+	// cannot be scoped to a single registered system.  Every system
+	// registered for this namespace therefore shares one namespace-wide
+	// notification rather than repeating the same full fan-out once per
+	// system.
+	log.V(2).Info("notifying system dependencies", "namespace", namespace)
+
 	return m.notifyControllers(namespace, systemDependencies)
 }
 
 func (m *PlatformManager) NotifyResource(object runtime.Object) error {
+	if IsPaused(object) {
+		log.V(2).Info("skipping notification for paused resource")
+		return nil
+	}
+
 	return m.notifyController(object)
 }
 
@@ -302,31 +500,52 @@ func (m *PlatformManager) GetKubernetesClient() client.Client {
 // namespace.  It is has not been created yet then false is returned in the
 // second return parameter.
 func (m *PlatformManager) GetPlatformClient(namespace string) *gophercloud.ServiceClient {
+	return m.GetPlatformClientFor(namespace, DefaultSystemName)
+}
+
+// GetPlatformClientFor returns the cached client for the given
+// (namespace, systemName) pair, falling back to the aged LRU pool if the
+// registry entry itself has not cached one.  nil is returned if neither
+// has a client for that system yet.
+func (m *PlatformManager) GetPlatformClientFor(namespace, systemName string) *gophercloud.ServiceClient {
+	key := SystemKey{Namespace: namespace, Name: systemName}
+
 	m.lock.Lock()
-	defer func() { m.lock.Unlock() }()
+	if ns, ok := m.registry.get(key); ok && ns.client != nil {
+		m.lock.Unlock()
+		return ns.client
+	}
+	m.lock.Unlock()
 
-	// Look for an existing client
-	if obj, ok := m.systems[namespace]; ok {
-		return obj.client
+	if c, ok := m.registry.pool.Get(key); ok {
+		return c
 	}
 
 	return nil
 }
 
 // ResetPlatformClient deletes the instance of the platform manager for a
-// given namespace.
+// given namespace, addressing the DefaultSystemName system.
 func (m *PlatformManager) ResetPlatformClient(namespace string) error {
+	return m.ResetPlatformClientFor(namespace, DefaultSystemName)
+}
+
+// ResetPlatformClientFor deletes the cached client for the given
+// (namespace, systemName) pair and notifies the system controller so
+// that it can rebuild one.
+func (m *PlatformManager) ResetPlatformClientFor(namespace, systemName string) error {
+	key := SystemKey{Namespace: namespace, Name: systemName}
+
 	m.lock.Lock()
-	defer func() { m.lock.Unlock() }()
+	ns, ok := m.registry.get(key)
+	if ok {
+		ns.client = nil
+	}
+	m.lock.Unlock()
 
-	// Look for an existing client
-	if obj, ok := m.systems[namespace]; ok {
-		if obj.client == nil {
-			// Already reset.
-			return nil
-		}
-		obj.client = nil
-	} else {
+	m.registry.pool.Remove(key)
+
+	if !ok {
 		// SystemNamespace doesn't exist yet
 		return nil
 	}
@@ -336,57 +555,63 @@ func (m *PlatformManager) ResetPlatformClient(namespace string) error {
 	return m.NotifySystemController(namespace)
 }
 
-// SetSystemReady allows setting the readiness state for a given namespace.
+// SetSystemReady allows setting the readiness state for a given
+// namespace.  Marking the system ready (value true) is refused while the
+// namespace's System CR is paused; the ready state is left unchanged in
+// that case and the next reconcile will try again once unpaused.
 func (m *PlatformManager) SetSystemReady(namespace string, value bool) {
+	if value && m.isNamespacePaused(namespace) {
+		log.V(2).Info("refusing to mark system ready while paused", "namespace", namespace)
+		return
+	}
+
+	ns := m.registry.getOrCreate(defaultKey(namespace))
+
 	m.lock.Lock()
 	defer func() { m.lock.Unlock() }()
 
-	if obj, ok := m.systems[namespace]; !ok {
-		m.systems[namespace] = &SystemNamespace{ready: value}
-	} else {
-		obj.ready = value
-	}
+	ns.ready = value
 }
 
 // GetSystemReady returns whether the system for the specified namespace
 // is ready for all controllers to reconcile their resources.
 func (m *PlatformManager) GetSystemReady(namespace string) bool {
+	ns, ok := m.registry.get(defaultKey(namespace))
+	if !ok {
+		return false
+	}
+
 	m.lock.Lock()
 	defer func() { m.lock.Unlock() }()
 
-	if obj, ok := m.systems[namespace]; !ok {
-		return false
-	} else {
-		return obj.ready
-	}
+	return ns.ready
 }
 
-// SetSystemReady allows setting the readiness state for a given namespace.
+// SetSystemType allows setting the system type for a given namespace.
 func (m *PlatformManager) SetSystemType(namespace string, value SystemType) {
+	ns := m.registry.getOrCreate(defaultKey(namespace))
+
 	m.lock.Lock()
 	defer func() { m.lock.Unlock() }()
 
-	if obj, ok := m.systems[namespace]; !ok {
-		m.systems[namespace] = &SystemNamespace{systemType: value}
-		log.Info("system type has been set", "type", value)
-	} else if obj.systemType != value {
-		obj.systemType = value
+	if ns.systemType != value {
+		ns.systemType = value
 		log.Info("system type has been updated", "type", value)
 	}
-
 }
 
-// GetSystemReady returns whether the system for the specified namespace
-// is ready for all controllers to reconcile their resources.
+// GetSystemType returns the system type recorded for the specified
+// namespace.
 func (m *PlatformManager) GetSystemType(namespace string) SystemType {
+	ns, ok := m.registry.get(defaultKey(namespace))
+	if !ok {
+		return ""
+	}
+
 	m.lock.Lock()
 	defer func() { m.lock.Unlock() }()
 
-	if obj, ok := m.systems[namespace]; !ok {
-		return ""
-	} else {
-		return obj.systemType
-	}
+	return ns.systemType
 }
 
 // StartMonitor starts the specified monitor, generates an event, and then
@@ -0,0 +1,101 @@
+/* SPDX-License-Identifier: Apache-2.0 */
+/* Copyright(c) 2022 Wind River Systems, Inc. */
+
+package manager
+
+import (
+	"sync"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+// channelBufferSize defines the depth of each NotifyBus subscription
+// channel.  It only needs to absorb a burst of notifications until the
+// controller workqueue attached to it drains them.
+const channelBufferSize = 1024
+
+// EnableNotifyBus selects whether resource notifications are delivered
+// through the in-process NotifyBus rather than the legacy
+// annotation-bump mechanism implemented by notifyController and
+// notifyControllers.  It is a package variable, rather than a constant,
+// so that it can be wired to a "--disable-notify-bus" style command line
+// flag while the annotation based path is retained as a fallback.
+var EnableNotifyBus = true
+
+// NotifyBus fans out resource change notifications to every controller
+// that has registered an interest in a given GroupVersionKind, without
+// mutating the resources being watched.  Each subscription owns its own
+// channel so that a burst of notifications for one kind can never starve
+// another.  This replaces the previous approach of patching a
+// "deployment-manager/notifications" counter annotation on every
+// dependent resource, which caused a GET+UPDATE round-trip per
+// notification and bumped resourceVersion on resources that had not
+// otherwise changed.
+type NotifyBus struct {
+	lock     sync.Mutex
+	channels map[schema.GroupVersionKind][]chan event.GenericEvent
+}
+
+// NewNotifyBus returns an empty NotifyBus ready to accept subscriptions.
+func NewNotifyBus() *NotifyBus {
+	return &NotifyBus{
+		channels: make(map[schema.GroupVersionKind][]chan event.GenericEvent),
+	}
+}
+
+// Subscribe registers a new source.Channel for the given
+// GroupVersionKind and wires it into the supplied controller using the
+// provided event handler.  Controllers call this from SetupWithManager,
+// for example:
+//
+//	err = bus.Subscribe(c, gvk, &handler.EnqueueRequestForObject{})
+func (b *NotifyBus) Subscribe(c controller.Controller, gvk schema.GroupVersionKind, h handler.EventHandler) error {
+	ch := make(chan event.GenericEvent, channelBufferSize)
+
+	b.lock.Lock()
+	b.channels[gvk] = append(b.channels[gvk], ch)
+	b.lock.Unlock()
+
+	return c.Watch(&source.Channel{Source: ch}, h)
+}
+
+// Publish enqueues a generic event for the given object on every channel
+// registered for its GroupVersionKind.  It is a no-op if no controller
+// has subscribed to that kind, which keeps it safe to call
+// unconditionally from notification paths that do not know in advance
+// whether a particular kind is being watched.
+//
+// The bus-wide lock is only held long enough to copy out the slice of
+// subscriber channels; sends happen afterwards, and are non-blocking, so
+// that one subscriber falling behind (or never starting its worker) can
+// neither stall every other Publish/Subscribe call nor block the
+// goroutine calling Publish.  A channel that is full has its event
+// dropped rather than queued further, since the NotifyBus only exists to
+// prompt a reconcile and a later notification will trigger the same
+// thing.
+func (b *NotifyBus) Publish(gvk schema.GroupVersionKind, key types.NamespacedName) {
+	object := &unstructured.Unstructured{}
+	object.SetGroupVersionKind(gvk)
+	object.SetNamespace(key.Namespace)
+	object.SetName(key.Name)
+
+	evt := event.GenericEvent{Meta: object, Object: object}
+
+	b.lock.Lock()
+	channels := append([]chan event.GenericEvent(nil), b.channels[gvk]...)
+	b.lock.Unlock()
+
+	for _, ch := range channels {
+		select {
+		case ch <- evt:
+		default:
+			log.Info("dropping notification, subscriber channel is full", "kind", gvk.Kind, "key", key)
+		}
+	}
+}
@@ -0,0 +1,239 @@
+/* SPDX-License-Identifier: Apache-2.0 */
+/* Copyright(c) 2022 Wind River Systems, Inc. */
+
+package manager
+
+import (
+	"container/list"
+	"sync"
+
+	"github.com/gophercloud/gophercloud"
+)
+
+// DefaultSystemName addresses the system used by callers that have not
+// been updated to name a specific one (namespace, systemName) pair.  In
+// the common single-system deployment this is the only system ever
+// registered for a namespace, and is resolved from the
+// SystemEndpointSecretName secret the same way a single system always
+// has been.
+const DefaultSystemName = "default"
+
+// DefaultClientPoolSize bounds how many gophercloud service clients are
+// kept warm across every namespace/system pair before the least
+// recently used one is aged out.
+const DefaultClientPoolSize = 32
+
+// SystemKey uniquely identifies one StarlingX system instance managed by
+// this operator.  A management cluster driving several regions, or a
+// distributed-cloud subcloud fan-out, registers more than one SystemKey
+// per namespace.
+type SystemKey struct {
+	Namespace string
+	Name      string
+}
+
+// defaultKey returns the SystemKey addressed by namespace-only callers.
+func defaultKey(namespace string) SystemKey {
+	return SystemKey{Namespace: namespace, Name: DefaultSystemName}
+}
+
+// String returns key in "namespace/name" form, suitable for use as a
+// workqueue/retry key or for recording on a CR's status.
+func (k SystemKey) String() string {
+	return k.Namespace + "/" + k.Name
+}
+
+// SystemRegistry tracks the cached client, readiness and type state for
+// every system this operator drives, keyed by SystemKey.  It replaces
+// the namespace-only map previously held directly by PlatformManager so
+// that a single namespace can host more than one system.
+type SystemRegistry struct {
+	lock  sync.Mutex
+	items map[SystemKey]*SystemNamespace
+	pool  *ClientPool
+}
+
+// NewSystemRegistry returns an empty SystemRegistry backed by a
+// DefaultClientPoolSize client pool.
+func NewSystemRegistry() *SystemRegistry {
+	return &SystemRegistry{
+		items: make(map[SystemKey]*SystemNamespace),
+		pool:  NewClientPool(DefaultClientPoolSize),
+	}
+}
+
+// getOrCreate returns the SystemNamespace registered for key, creating an
+// empty one on first use.
+func (r *SystemRegistry) getOrCreate(key SystemKey) *SystemNamespace {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	ns, ok := r.items[key]
+	if !ok {
+		ns = &SystemNamespace{}
+		r.items[key] = ns
+	}
+
+	return ns
+}
+
+// get returns the SystemNamespace registered for key, if any.
+func (r *SystemRegistry) get(key SystemKey) (*SystemNamespace, bool) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	ns, ok := r.items[key]
+	return ns, ok
+}
+
+// keysInNamespace returns every SystemKey currently registered for
+// namespace.
+func (r *SystemRegistry) keysInNamespace(namespace string) []SystemKey {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	var keys []SystemKey
+	for key := range r.items {
+		if key.Namespace == namespace {
+			keys = append(keys, key)
+		}
+	}
+
+	return keys
+}
+
+// ClientPool caches gophercloud service clients, modeled on client-go's
+// dynamic.NewClientPool: entries are built on demand by
+// BuildPlatformClientFor, which then Puts them here, and are aged out on
+// an LRU basis, and the underlying
+// gophercloud.ProviderClient (which holds the authentication token) is
+// shared by every system client built for the same namespace so that a
+// second region does not have to re-authenticate.
+type ClientPool struct {
+	lock      sync.Mutex
+	maxSize   int
+	order     *list.List
+	elements  map[SystemKey]*list.Element
+	clients   map[SystemKey]*gophercloud.ServiceClient
+	providers map[string]*gophercloud.ProviderClient
+}
+
+// NewClientPool returns an empty ClientPool that keeps at most maxSize
+// service clients warm.  A maxSize of 0 disables aging entirely.
+func NewClientPool(maxSize int) *ClientPool {
+	return &ClientPool{
+		maxSize:   maxSize,
+		order:     list.New(),
+		elements:  make(map[SystemKey]*list.Element),
+		clients:   make(map[SystemKey]*gophercloud.ServiceClient),
+		providers: make(map[string]*gophercloud.ProviderClient),
+	}
+}
+
+// Get returns the cached service client for key, marking it as most
+// recently used.
+func (p *ClientPool) Get(key SystemKey) (*gophercloud.ServiceClient, bool) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	c, ok := p.clients[key]
+	if ok {
+		p.touch(key)
+	}
+
+	return c, ok
+}
+
+// Put caches c for key, evicting the least recently used entry if the
+// pool is over capacity.
+func (p *ClientPool) Put(key SystemKey, c *gophercloud.ServiceClient) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.clients[key] = c
+	p.touch(key)
+	p.evict()
+}
+
+// touch marks key as most recently used, registering it in the LRU list
+// if this is its first use.
+func (p *ClientPool) touch(key SystemKey) {
+	if el, ok := p.elements[key]; ok {
+		p.order.MoveToFront(el)
+		return
+	}
+
+	p.elements[key] = p.order.PushFront(key)
+}
+
+// evict removes least recently used entries until the pool is back
+// within its configured capacity.
+func (p *ClientPool) evict() {
+	for p.maxSize > 0 && p.order.Len() > p.maxSize {
+		oldest := p.order.Back()
+		if oldest == nil {
+			return
+		}
+
+		key := oldest.Value.(SystemKey)
+		p.order.Remove(oldest)
+		delete(p.elements, key)
+		delete(p.clients, key)
+
+		log.V(2).Info("aged out platform client", "namespace", key.Namespace, "system", key.Name)
+	}
+}
+
+// Remove evicts the cached client for key, if any.
+func (p *ClientPool) Remove(key SystemKey) {
+	p.lock.Lock()
+	defer func() { p.lock.Unlock() }()
+
+	if el, ok := p.elements[key]; ok {
+		p.order.Remove(el)
+		delete(p.elements, key)
+	}
+
+	delete(p.clients, key)
+}
+
+// Provider returns the gophercloud.ProviderClient shared by every system
+// client built for namespace, if BuildPlatformClient has registered one.
+func (p *ClientPool) Provider(namespace string) (*gophercloud.ProviderClient, bool) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	provider, ok := p.providers[namespace]
+	return provider, ok
+}
+
+// SetProvider registers provider to be reused by every system client
+// subsequently built for namespace.
+func (p *ClientPool) SetProvider(namespace string, provider *gophercloud.ProviderClient) {
+	p.lock.Lock()
+	defer func() { p.lock.Unlock() }()
+
+	p.providers[namespace] = provider
+}
+
+// RemoveNamespace evicts every cached client and the shared provider for
+// namespace, forcing the next BuildPlatformClient call to re-authenticate.
+func (p *ClientPool) RemoveNamespace(namespace string) {
+	p.lock.Lock()
+	defer func() { p.lock.Unlock() }()
+
+	delete(p.providers, namespace)
+
+	for key := range p.clients {
+		if key.Namespace != namespace {
+			continue
+		}
+
+		if el, ok := p.elements[key]; ok {
+			p.order.Remove(el)
+			delete(p.elements, key)
+		}
+
+		delete(p.clients, key)
+	}
+}
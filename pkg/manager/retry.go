@@ -0,0 +1,192 @@
+/* SPDX-License-Identifier: Apache-2.0 */
+/* Copyright(c) 2022 Wind River Systems, Inc. */
+
+package manager
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	perrors "github.com/pkg/errors"
+	"golang.org/x/time/rate"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/wind-river/cloud-platform-deployment-manager/pkg/apis/starlingx/v1"
+)
+
+// Default rate limit and retry budget applied to a namespace the first
+// time WithRetry is used against it.  These are deliberately conservative
+// since a StarlingX endpoint under load, or mid-upgrade, can take a long
+// time to start responding again.
+const (
+	DefaultRetryQPS     = 1
+	DefaultRetryBurst   = 10
+	DefaultMaxRetries   = 5
+	retryEventReason    = "RetryFailed"
+	fatalErrEventReason = "FatalError"
+)
+
+// FatalClientError defines an error which must never be retried.  Unlike
+// ClientError, encountering a FatalClientError short-circuits WithRetry
+// and immediately surfaces an Event on the System CR rather than
+// requeuing.
+type FatalClientError struct {
+	BaseError
+}
+
+// NewFatalClientError defines a wrapper to correctly instantiate a fatal
+// client error.
+func NewFatalClientError(msg string) error {
+	return perrors.WithStack(FatalClientError{BaseError{msg}})
+}
+
+// RequeueAfter is returned by WithRetry in place of a transient error
+// while the namespace's retry budget still has room.  It signals to the
+// caller (typically a reconciler) that it should return
+// ctrl.Result{RequeueAfter: Delay} to controller-runtime rather than
+// retrying inline, so a flaky endpoint backs off the workqueue instead of
+// blocking a reconciler goroutine.
+type RequeueAfter struct {
+	BaseError
+	Delay time.Duration
+}
+
+// NewRequeueAfter wraps cause in a RequeueAfter carrying delay.  WithRetry
+// uses this to signal a requeue instead of sleeping; it is also exported
+// so that FakePlatformManager can simulate the same classification
+// deterministically in tests.
+func NewRequeueAfter(delay time.Duration, cause error) error {
+	return perrors.WithStack(RequeueAfter{BaseError{cause.Error()}, delay})
+}
+
+// IsRequeueAfter reports whether err (or its cause) is a RequeueAfter and,
+// if so, returns the delay the caller should wait before requeuing.
+func IsRequeueAfter(err error) (time.Duration, bool) {
+	if r, ok := perrors.Cause(err).(RequeueAfter); ok {
+		return r.Delay, true
+	}
+
+	return 0, false
+}
+
+// RetryableFunc defines a unit of work that WithRetry will repeat until
+// it succeeds, returns a FatalClientError, or exhausts its retry budget.
+type RetryableFunc func() error
+
+// RetryManager tracks the retry budget and rate limiting state for a
+// single namespace.  Requests are throttled through a token bucket
+// (mirroring k8s.io/client-go/util/workqueue.NewTokenBucketRateLimiter)
+// and bounded by a maximum number of attempts per key, similar to the
+// retries.Count pattern used by the ImageChangeController.
+type RetryManager struct {
+	limiter    workqueue.RateLimiter
+	maxRetries int
+}
+
+// NewRetryManager returns a RetryManager configured with the given token
+// bucket rate (qps/burst) and maximum retry count.
+func NewRetryManager(qps float64, burst, maxRetries int) *RetryManager {
+	return &RetryManager{
+		limiter: workqueue.NewMaxOfRateLimiter(
+			workqueue.DefaultControllerRateLimiter(),
+			&workqueue.BucketRateLimiter{Limiter: rate.NewLimiter(rate.Limit(qps), burst)},
+		),
+		maxRetries: maxRetries,
+	}
+}
+
+// allow reports whether key is still within its retry budget and, if so,
+// how long the caller should wait before the next attempt.
+func (r *RetryManager) allow(key string) (time.Duration, bool) {
+	if r.limiter.NumRequeues(key) >= r.maxRetries {
+		return 0, false
+	}
+
+	return r.limiter.When(key), true
+}
+
+// forget clears the retry history for key so that a future failure
+// starts a fresh backoff sequence.
+func (r *RetryManager) forget(key string) {
+	r.limiter.Forget(key)
+}
+
+// retryManagerFor returns the RetryManager for namespace, creating one
+// with the default rate limit and retry budget on first use.
+func (m *PlatformManager) retryManagerFor(namespace string) *RetryManager {
+	m.lock.Lock()
+	defer func() { m.lock.Unlock() }()
+
+	if m.retries == nil {
+		m.retries = make(map[string]*RetryManager)
+	}
+
+	rm, ok := m.retries[namespace]
+	if !ok {
+		rm = NewRetryManager(DefaultRetryQPS, DefaultRetryBurst, DefaultMaxRetries)
+		m.retries[namespace] = rm
+	}
+
+	return rm
+}
+
+// WithRetry runs fn exactly once and classifies the result against the
+// namespace's retry budget instead of retrying inline:
+//
+//   - nil error: the namespace's retry history for key is cleared and nil
+//     is returned.
+//   - FatalClientError: the retry history is cleared, an Event is
+//     recorded on the namespace's System CR, and the error is returned
+//     as-is so the caller stops retrying.
+//   - any other error, with budget remaining: a RequeueAfter error is
+//     returned carrying the token-bucket-clamped backoff; the caller
+//     (typically a reconciler) should translate it into
+//     ctrl.Result{RequeueAfter: delay} via IsRequeueAfter and let
+//     controller-runtime requeue the request, rather than blocking on a
+//     sleep.
+//   - any other error, with the budget exhausted: an Event is recorded on
+//     the System CR and the error is returned wrapped with an "exceeded
+//     maximum retries" message so the caller stops retrying.
+func (m *PlatformManager) WithRetry(namespace string, key string, fn RetryableFunc) error {
+	rm := m.retryManagerFor(namespace)
+
+	err := fn()
+	if err == nil {
+		rm.forget(key)
+		return nil
+	}
+
+	if _, ok := perrors.Cause(err).(FatalClientError); ok {
+		rm.forget(key)
+		m.recordRetryEvent(namespace, fatalErrEventReason, key, err)
+		return err
+	}
+
+	delay, ok := rm.allow(key)
+	if !ok {
+		m.recordRetryEvent(namespace, retryEventReason, key, err)
+		return perrors.Wrapf(err, "exceeded maximum retries for %q", key)
+	}
+
+	log.Info("scheduling retry after transient error", "namespace", namespace, "key", key, "delay", delay, "error", err)
+	return NewRequeueAfter(delay, err)
+}
+
+// recordRetryEvent emits a Kubernetes Event on the System CR belonging
+// to namespace describing why a retried operation gave up.
+func (m *PlatformManager) recordRetryEvent(namespace, reason, key string, err error) {
+	systems := &v1.SystemList{}
+	opts := client.ListOptions{}
+	opts.InNamespace(namespace)
+	if listErr := m.GetClient().List(context.TODO(), &opts, systems); listErr != nil {
+		log.Info("failed to query system list for retry event", "namespace", namespace, "error", listErr)
+		return
+	}
+
+	for i := range systems.Items {
+		system := &systems.Items[i]
+		m.GetRecorder("platform-manager").Eventf(system, "Warning", reason, "%s: %s", key, err)
+	}
+}